@@ -0,0 +1,195 @@
+package gmail
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// WriteEML writes the message as an .eml file with CRLF line endings, suitable
+// for saving to disk, opening in a third-party mail client, or feeding back
+// into ParseEML. It behaves like WriteTo but guarantees a Date header is
+// present, adding the current time if the caller didn't set one. Base64
+// parts are written without line wrapping, so a large attachment yields
+// lines longer than the 998 characters RFC 5322 allows.
+func (m *GoogleMessage) WriteEML(w io.Writer) error {
+	if m.Header == nil {
+		m.Header = make(textproto.MIMEHeader)
+	}
+	if m.Header.Get("Date") == "" {
+		m.Header.Set("Date", time.Now().Format(time.RFC1123Z))
+	}
+
+	_, err := m.WriteTo(w)
+	return err
+}
+
+// ParseEML parses an RFC-5322 message - such as one written by WriteEML, or
+// exported from any other mail client - into a GoogleMessage. It walks the
+// MIME tree, decoding quoted-printable and base64 parts, and repopulates
+// Header, the body parts and the attachments (including inline parts with a
+// Content-ID) so the result can be inspected or handed straight to Send.
+func ParseEML(r io.Reader) (*GoogleMessage, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse eml: %w", err)
+	}
+
+	m := &GoogleMessage{Header: textproto.MIMEHeader(msg.Header)}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		data, err := io.ReadAll(msg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("parse eml: %w", err)
+		}
+		if err := m.SetBody(data, nil, Auto); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		err = m.parseEMLMultipart(msg.Body, params["boundary"])
+	} else {
+		var data []byte
+		if data, err = decodeEMLPart(msg.Header, msg.Body); err == nil {
+			err = m.attachEMLPart(msg.Header, mediaType, data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The content fields now live on the parts built from them; dropping
+	// them from the message header keeps WriteTo from writing them twice.
+	for _, k := range contentHeaderFields {
+		m.Header.Del(k)
+	}
+	return m, nil
+}
+
+// ParseEMLFile opens the .eml file at path and parses it with ParseEML.
+func ParseEMLFile(path string) (*GoogleMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse eml file: %w", err)
+	}
+	defer f.Close()
+
+	return ParseEML(bufio.NewReader(f))
+}
+
+// parseEMLMultipart walks a multipart body, recursing into any nested
+// multipart/* parts, and attaches each leaf part to the message.
+func (m *GoogleMessage) parseEMLMultipart(r io.Reader, boundary string) error {
+	if boundary == "" {
+		return errors.New("parse eml: missing multipart boundary")
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parse eml: %w", err)
+		}
+
+		header := mail.Header(part.Header)
+
+		mediaType, nestedParams, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if err := m.parseEMLMultipart(part, nestedParams["boundary"]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := decodeEMLPart(header, part)
+		if err != nil {
+			return err
+		}
+
+		if err := m.attachEMLPart(header, mediaType, data); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeEMLPart decodes the body of a part according to its
+// Content-Transfer-Encoding, defaulting to verbatim for anything else
+// (7bit, 8bit, binary, or unset).
+func decodeEMLPart(header mail.Header, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(r))
+	case "base64":
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+	default:
+		return io.ReadAll(r)
+	}
+}
+
+// contentHeaderFields lists the headers that describe a part's content
+// rather than the envelope it arrived in or the message it belongs to.
+var contentHeaderFields = []string{
+	"Content-Type", "Content-Transfer-Encoding", "Content-Disposition", "Content-ID",
+}
+
+// contentHeader copies only the content-describing fields out of header,
+// so a part built from it never picks up unrelated envelope fields - such
+// as From/To/Subject/Date when header is a top-level message's header.
+func contentHeader(header mail.Header) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	for _, k := range contentHeaderFields {
+		if v := header.Get(k); v != "" {
+			h.Set(k, v)
+		}
+	}
+	return h
+}
+
+// attachEMLPart adds a decoded part to the message, routing it to the
+// matching body slot (_bodyTEXT/_bodyHTML) when it looks like a
+// top-level text part, or as an attachment otherwise.
+func (m *GoogleMessage) attachEMLPart(header mail.Header, mediaType string, data []byte) error {
+	h := contentHeader(header)
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+
+	if filename == "" && disposition != "attachment" && disposition != "inline" {
+		switch mediaType {
+		case "text/plain":
+			return m.SetBody(data, &h, Text)
+		case "text/html":
+			return m.SetBody(data, &h, HTML)
+		}
+	}
+
+	if filename == "" {
+		if cid := strings.Trim(h.Get("Content-ID"), "<>"); cid != "" {
+			filename = cid
+		} else {
+			filename = fmt.Sprintf("attachment-%d", len(m.parts)+1)
+		}
+	}
+
+	return m.Attach(filename, data, &h)
+}
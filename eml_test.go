@@ -0,0 +1,82 @@
+package gmail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseEMLSinglePartRoundTrip(t *testing.T) {
+	const raw = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Subject: hi\r\n" +
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
+		"\r\n" +
+		"hello world"
+
+	m, err := ParseEML(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseEML: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.String()
+
+	// Envelope headers must appear exactly once - a parsed single-part
+	// message must not duplicate its header by also aliasing it onto the
+	// body part.
+	for _, want := range []string{"From: a@example.com", "To: b@example.com", "Subject: hi"} {
+		if n := strings.Count(out, want); n != 1 {
+			t.Errorf("expected %q exactly once, got %d times in:\n%s", want, n, out)
+		}
+	}
+
+	if !strings.Contains(out, "hello world") {
+		t.Errorf("expected body to survive the round trip, got:\n%s", out)
+	}
+}
+
+func TestParseEMLMultipartRoundTrip(t *testing.T) {
+	const raw = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Subject: hi\r\n" +
+		"Content-Type: multipart/alternative; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"plain body\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<p>html body</p>\r\n" +
+		"--BOUNDARY--\r\n"
+
+	m, err := ParseEML(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseEML: %v", err)
+	}
+
+	if !m.Has(_bodyTEXT) {
+		t.Error("expected a text body part")
+	}
+	if !m.Has(_bodyHTML) {
+		t.Error("expected an html body part")
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.String()
+
+	if strings.Count(out, "Subject: hi") != 1 {
+		t.Errorf("expected Subject exactly once, got:\n%s", out)
+	}
+	if !strings.Contains(out, "plain body") || !strings.Contains(out, "<p>html body</p>") {
+		t.Errorf("expected both bodies to survive the round trip, got:\n%s", out)
+	}
+}
@@ -0,0 +1,67 @@
+package gmail
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrInvalidMessage is returned when the message could not be serialized
+// into a valid MIME payload before being sent.
+var ErrInvalidMessage = errors.New("gmail: invalid message")
+
+// ErrUnauthorized indicates the Gmail API rejected the request because the
+// access token is missing, expired, or lacks the required scope.
+var ErrUnauthorized = errors.New("gmail: unauthorized")
+
+// ErrRateLimited indicates the Gmail API rejected the request because the
+// caller exceeded a usage quota or rate limit.
+var ErrRateLimited = errors.New("gmail: rate limited")
+
+// ErrGoogleStatus wraps a non-2xx response from the Gmail API that doesn't
+// map to one of the more specific sentinel errors above. Callers can use
+// errors.As to inspect the status code and response body.
+type ErrGoogleStatus struct {
+	Code int
+	Body string
+}
+
+func (e *ErrGoogleStatus) Error() string {
+	return fmt.Sprintf("gmail: google api returned status %d: %s", e.Code, e.Body)
+}
+
+// ErrGoogleResponse wraps a failure that never made it to a structured
+// googleapi.Error, such as a transport error or a malformed response body.
+type ErrGoogleResponse struct {
+	Raw string
+}
+
+func (e *ErrGoogleResponse) Error() string {
+	return fmt.Sprintf("gmail: malformed google api response: %s", e.Raw)
+}
+
+// wrapGoogleError classifies err - expected to come back from a
+// Users.Messages call - into one of the typed errors above, so callers can
+// use errors.Is/errors.As to implement retry, backoff, or token-refresh
+// logic instead of inspecting a raw googleapi.Error.
+func wrapGoogleError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return fmt.Errorf("%w", &ErrGoogleResponse{Raw: err.Error()})
+	}
+
+	switch gerr.Code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrUnauthorized, gerr.Message)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", ErrRateLimited, gerr.Message)
+	default:
+		return fmt.Errorf("%w", &ErrGoogleStatus{Code: gerr.Code, Body: gerr.Body})
+	}
+}
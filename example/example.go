@@ -1,12 +1,14 @@
 package example
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"mime"
 	"net/mail"
 	"net/textproto"
 	"strings"
+	"time"
 
 	"github.com/xantdev/gmail-go"
 )
@@ -84,11 +86,11 @@ func newGoogleMessage(email Email, customHeaders map[string]string, token string
 	}
 
 	if len(email.Cc) > 0 {
-		header.Set("cc", email.Cc.ArrList(true)) // case here matters - anything but cc results in a corrupt header :(
+		header.Set("Cc", email.Cc.ArrList(true))
 	}
 
 	if len(email.Bcc) > 0 {
-		header.Set("bcc", email.Bcc.ArrList(true)) // case here matters - anything but bcc results in a corrupt header :(
+		header.Set("Bcc", email.Bcc.ArrList(true))
 	}
 
 	if email.Subject != "" {
@@ -96,7 +98,7 @@ func newGoogleMessage(email Email, customHeaders map[string]string, token string
 	}
 
 	if email.InReplyTo != "" && len(email.References) > 0 {
-		header.Set("InReplyTo", email.InReplyTo)
+		header.Set("In-Reply-To", email.InReplyTo)
 		references := strings.Join(email.References, ",")
 		header.Set("References", references)
 	}
@@ -151,7 +153,10 @@ func main() {
 		panic(err)
 	}
 
-	messageID, err := msg.Send(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	messageID, err := msg.SendContext(ctx, gmail.WithRetry(3, time.Second, 30*time.Second))
 	if err != nil {
 		panic(err)
 	}
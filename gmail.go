@@ -2,7 +2,6 @@ package gmail
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -11,13 +10,14 @@ import (
 	"mime/multipart"
 	"mime/quotedprintable"
 	"net/http"
+	"net/mail"
 	"net/textproto"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
-	"google.golang.org/api/gmail/v1"
-	"google.golang.org/api/option"
 )
 
 // BodyType - what type of body to set
@@ -116,7 +116,9 @@ func (m *GoogleMessage) Attach(name string, data []byte, headers *textproto.MIME
 
 	m.parts[name] = &googlePart{
 		header: h,
-		data:   data,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
 	}
 
 	return nil
@@ -153,18 +155,46 @@ func (m *GoogleMessage) Has(name string) bool {
 	return ok
 }
 
-// WriteTo generates and writes the text representation of mail messages.
-func (m *GoogleMessage) WriteTo(w io.Writer) (int64, error) {
-	var numBytes int64
+// Embed attaches an inline resource - typically an image referenced from an
+// HTML body via a "cid:" URL - to the message. The part is marked
+// Content-Disposition: inline and given a Content-ID derived from name, so
+// an HTML body can reference it as cid:name.
+func (m *GoogleMessage) Embed(name string, data []byte, headers *textproto.MIMEHeader) error {
+	var h = make(textproto.MIMEHeader)
+	if headers != nil {
+		h = *headers
+	}
+
+	if h.Get("Content-Disposition") == "" {
+		h.Set("Content-Disposition", fmt.Sprintf("inline; filename=%s", name))
+	}
+	if h.Get("Content-ID") == "" {
+		h.Set("Content-ID", fmt.Sprintf("<%s>", name))
+	}
 
+	return m.Attach(name, data, &h)
+}
+
+// WriteTo generates and writes the text representation of mail messages. The
+// parts are nested into the standard multipart/mixed (attachments) ->
+// multipart/related (inline resources) -> multipart/alternative (text/html)
+// tree, omitting any level that has nothing to contribute.
+func (m *GoogleMessage) WriteTo(w io.Writer) (int64, error) {
 	if len(m.parts) == 0 {
 		return 0, errors.New("contents are undefined")
 	}
 
+	if m.Header == nil {
+		m.Header = make(textproto.MIMEHeader)
+	}
+
 	var headers = make(textproto.MIMEHeader)
 	if m.Header.Get("MIME-Version") == "" {
 		headers.Set("MIME-Version", "1.0")
 	}
+	if m.Header.Get("Date") == "" {
+		headers.Set("Date", time.Now().Format(time.RFC1123Z))
+	}
 
 	// copy the primary header of the message
 	for k, v := range m.Header {
@@ -173,120 +203,166 @@ func (m *GoogleMessage) WriteTo(w io.Writer) (int64, error) {
 		}
 	}
 
-	// check that only defined the basic message, no attachments
-	if len(m.parts) == 1 && m.Has(_body) {
-		body := m.parts[_body]
-		for k, v := range body.header {
+	bodies, inline, attachments := m.classifyParts()
+
+	var cw = &countingWriter{w: w}
+
+	// a message with a single text/html body and nothing else needs no
+	// multipart wrapper at all
+	if len(bodies) == 1 && len(inline) == 0 && len(attachments) == 0 {
+		for k, v := range bodies[0].header {
 			for _, v2 := range v {
 				headers.Add(k, v2)
 			}
 		}
 
-		numBytes += int64(writeEmailHeaders(w, headers))
+		writeEmailHeaders(cw, headers)
 
-		if bytesWritten, err := body.writeGoogleData(w); err != nil {
-			return numBytes + int64(bytesWritten), err
-		}
-		return numBytes, nil
+		_, err := bodies[0].writeGoogleData(cw)
+		return cw.n, err
 	}
 
-	// there are attached files
-	var mw = multipart.NewWriter(w)
+	var mw = multipart.NewWriter(cw)
 	defer mw.Close()
-	headers.Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%s", mw.Boundary()))
-
-	writeEmailHeaders(w, headers)
-
-	for _, p := range m.parts {
-		pw, err := mw.CreatePart(p.header)
-		if err != nil {
-			return numBytes, err
-		}
 
-		if bytesWritten, err := p.writeGoogleData(pw); err != nil {
-			return numBytes + int64(bytesWritten), err
-		}
-	}
-	return numBytes, nil
-}
-
-// Send sends the message through GMail.
-// Returns the Message-Id header for the sent email
-func (m *GoogleMessage) Send() (string, error) {
-	var buf bytes.Buffer
-	m.WriteTo(&buf)
-
-	body := base64.RawURLEncoding.EncodeToString(buf.Bytes())
-
-	var gmailMessage = &gmail.Message{Raw: body}
-
-	srv, err := gmail.NewService(context.Background(), option.WithTokenSource(m), option.WithUserAgent("XANTDev/gmail-go"))
-
-	resp, err := srv.Users.Messages.Send("me", gmailMessage).Do()
-	if err != nil {
-		return "", err
-	}
-
-	sentMsg, err := srv.Users.Messages.Get("me", resp.Id).Do()
-	if err != nil {
-		return "", err
-	}
-
-	var messageID string
-	if sentMsg.Payload != nil {
-		if sentMsg.Payload.Headers != nil {
-			for _, v := range sentMsg.Payload.Headers {
-				if v.Name == "Message-Id" {
-					messageID = v.Value
-					break
-				}
-			}
-		}
+	headers.Set("Content-Type", fmt.Sprintf("%s; boundary=%s", outermostContentType(inline, attachments), mw.Boundary()))
+	writeEmailHeaders(cw, headers)
+
+	var err error
+	switch {
+	case len(attachments) > 0:
+		err = writeMixed(mw, bodies, inline, attachments)
+	case len(inline) > 0:
+		// mw is already the declared multipart/related writer here, so the
+		// alternative content and inline parts are written directly onto it
+		// rather than through writeRelated, which would nest them in a
+		// second, redundant multipart/related part.
+		err = writeRelatedBodies(mw, bodies, inline)
+	default:
+		// mw is already the declared multipart/alternative writer here, so
+		// the bodies are written directly onto it rather than through
+		// writeAlternative, which would nest them in a second, redundant
+		// multipart/alternative part.
+		err = writeBodies(mw, bodies)
 	}
 
-	return messageID, nil
+	return cw.n, err
 }
 
 // googlePart describes googlePart email message: the file or message.
 type googlePart struct {
-	header textproto.MIMEHeader // headers
-	data   []byte               // content
+	header textproto.MIMEHeader          // headers
+	open   func() (io.ReadCloser, error) // opens the raw, unencoded content
 }
 
-// writeGoogleData writes the contents of the message file with maintain the coding
-// system. At the moment only implemented quoted-printable and base64 encoding.
-// For all others, an error is returned.
+// writeGoogleData streams the part's content through w, applying the
+// encoding named by its Content-Transfer-Encoding. At the moment only
+// quoted-printable and base64 are implemented; anything else is an error.
+// The content is opened and copied in a single pass rather than buffered,
+// so attachments added via AttachFile never have to fit in memory whole.
 func (p *googlePart) writeGoogleData(w io.Writer) (numBytes int, err error) {
+	r, err := p.open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var enc io.WriteCloser
 	switch name := p.header.Get("Content-Transfer-Encoding"); name {
 	case "quoted-printable":
-		enc := quotedprintable.NewWriter(w)
-		numBytes, err = enc.Write(p.data)
-		enc.Close()
+		enc = quotedprintable.NewWriter(w)
 	case "base64":
-		enc := base64.NewEncoder(base64.StdEncoding, w)
-		numBytes, err = enc.Write(p.data)
-		enc.Close()
+		enc = base64.NewEncoder(base64.StdEncoding, w)
 	default:
-		err = fmt.Errorf("unsupported transform encoding: %v", name)
+		return 0, fmt.Errorf("unsupported transform encoding: %v", name)
 	}
-	return numBytes, err
+
+	written, copyErr := io.Copy(enc, r)
+	closeErr := enc.Close()
+
+	numBytes = int(written)
+	if copyErr != nil {
+		return numBytes, copyErr
+	}
+	return numBytes, closeErr
+}
+
+// headerOrder lists the conventional header order real MUAs use. Headers
+// not listed here are written afterwards, sorted alphabetically.
+var headerOrder = []string{
+	"From", "To", "Cc", "Bcc", "Reply-To", "Subject", "Date", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type",
+	"Content-Transfer-Encoding", "Content-Disposition",
 }
 
-// writeEmailHeaders writes the header of the message or file.
+// writeEmailHeaders writes the header of the message or file, canonicalizing
+// key casing and writing them in a deterministic, conventional order so the
+// output - and anything hashed or signed over it, like DKIM - is stable.
 func writeEmailHeaders(w io.Writer, h textproto.MIMEHeader) (numBytes int) {
-	var keys = make([]string, 0, len(h))
-	for k := range h {
+	canonical := make(textproto.MIMEHeader, len(h))
+	for k, v := range h {
+		canonical[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	priority := make(map[string]int, len(headerOrder))
+	for i, k := range headerOrder {
+		priority[textproto.CanonicalMIMEHeaderKey(k)] = i
+	}
+
+	keys := make([]string, 0, len(canonical))
+	for k := range canonical {
 		keys = append(keys, k)
 	}
 
+	sort.Slice(keys, func(i, j int) bool {
+		pi, oki := priority[keys[i]]
+		pj, okj := priority[keys[j]]
+		switch {
+		case oki && okj:
+			return pi < pj
+		case oki:
+			return true
+		case okj:
+			return false
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+
 	for _, k := range keys {
-		numBytes += writeHeader(w, k, h[k]...)
+		v := canonical[k]
+		if k == "To" || k == "Cc" || k == "Bcc" {
+			v = encodeAddressList(v)
+		}
+		numBytes += writeHeader(w, k, v...)
 	}
 	fmt.Fprintf(w, "\r\n") // add the offset from the header
 
 	return
 }
 
+// encodeAddressList MIME-encodes the display name of each address in v,
+// matching what real MUAs produce for non-ASCII recipients. Values that
+// don't parse as an address list are passed through unchanged, since they
+// may already be encoded or otherwise non-standard.
+func encodeAddressList(v []string) []string {
+	encoded := make([]string, len(v))
+	for i, s := range v {
+		addrs, err := mail.ParseAddressList(s)
+		if err != nil {
+			encoded[i] = s
+			continue
+		}
+
+		names := make([]string, len(addrs))
+		for j, addr := range addrs {
+			names[j] = addr.String()
+		}
+		encoded[i] = strings.Join(names, ", ")
+	}
+	return encoded
+}
+
 func writeHeader(w io.Writer, k string, v ...string) (numBytes int) {
 	bytesWritten, _ := io.WriteString(w, k)
 	numBytes += bytesWritten
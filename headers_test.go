@@ -0,0 +1,65 @@
+package gmail
+
+import (
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestWriteEmailHeadersOrderAndCasing(t *testing.T) {
+	h := make(textproto.MIMEHeader)
+	h.Set("subject", "hi")
+	h.Set("x-custom", "value")
+	h.Set("from", "a@example.com")
+	h.Set("to", "b@example.com")
+
+	var buf strings.Builder
+	writeEmailHeaders(&buf, h)
+	out := buf.String()
+
+	from := strings.Index(out, "From:")
+	to := strings.Index(out, "To:")
+	subject := strings.Index(out, "Subject:")
+	custom := strings.Index(out, "X-Custom:")
+
+	if from == -1 || to == -1 || subject == -1 || custom == -1 {
+		t.Fatalf("expected all headers to be canonicalized, got:\n%s", out)
+	}
+	if !(from < to && to < subject && subject < custom) {
+		t.Errorf("expected From, To, Subject before the unlisted X-Custom header, got:\n%s", out)
+	}
+}
+
+func TestWriteEmailHeadersEncodesNonASCIIDisplayNames(t *testing.T) {
+	h := make(textproto.MIMEHeader)
+	h.Set("To", "Bjö rn <bjorn@example.com>")
+
+	var buf strings.Builder
+	writeEmailHeaders(&buf, h)
+	out := buf.String()
+
+	if strings.Contains(out, "Bjö rn") {
+		t.Errorf("expected the non-ASCII display name to be MIME-encoded, got:\n%s", out)
+	}
+	if !strings.Contains(out, "=?utf-8?q?") && !strings.Contains(out, "=?utf-8?b?") {
+		t.Errorf("expected a quoted-printable or base64 encoded word, got:\n%s", out)
+	}
+	if !strings.Contains(out, "<bjorn@example.com>") {
+		t.Errorf("expected the address itself to survive unencoded, got:\n%s", out)
+	}
+}
+
+func TestWriteEmailHeadersAreDeterministic(t *testing.T) {
+	h := make(textproto.MIMEHeader)
+	h.Set("Zebra", "z")
+	h.Set("Apple", "a")
+	h.Set("Mango", "m")
+
+	var first, second strings.Builder
+	writeEmailHeaders(&first, h)
+	writeEmailHeaders(&second, h)
+
+	if first.String() != second.String() {
+		t.Errorf("expected identical output across calls, got:\n%s\n---\n%s", first.String(), second.String())
+	}
+}
@@ -0,0 +1,219 @@
+package gmail
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+)
+
+// bodyOrder lists the body slots from least to most preferred, which is the
+// order RFC 2046 requires for multipart/alternative.
+var bodyOrder = []string{_bodyTEXT, _body, _bodyHTML}
+
+// classifyParts splits m.parts into the text bodies (ordered per
+// bodyOrder), the inline resources added via Embed, and the remaining
+// attachments. Inline resources and attachments are each sorted by name so
+// WriteTo produces deterministic output - important for DKIM signing,
+// snapshot tests, and anything else that hashes the result.
+func (m *GoogleMessage) classifyParts() (bodies, inline, attachments []*googlePart) {
+	for _, name := range bodyOrder {
+		if p, ok := m.parts[name]; ok {
+			bodies = append(bodies, p)
+		}
+	}
+
+	var inlineNames, attachmentNames []string
+	for name := range m.parts {
+		switch name {
+		case _body, _bodyTEXT, _bodyHTML:
+			continue
+		}
+
+		if isInline(m.parts[name].header) {
+			inlineNames = append(inlineNames, name)
+		} else {
+			attachmentNames = append(attachmentNames, name)
+		}
+	}
+
+	sort.Strings(inlineNames)
+	sort.Strings(attachmentNames)
+
+	for _, name := range inlineNames {
+		inline = append(inline, m.parts[name])
+	}
+	for _, name := range attachmentNames {
+		attachments = append(attachments, m.parts[name])
+	}
+
+	return bodies, inline, attachments
+}
+
+// isInline reports whether a part's Content-Disposition is "inline".
+func isInline(h textproto.MIMEHeader) bool {
+	disposition, _, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	return disposition == "inline"
+}
+
+// outermostContentType picks the content type of the outermost multipart
+// wrapper: multipart/mixed when there are attachments, multipart/related
+// when there are inline resources but no attachments, otherwise
+// multipart/alternative.
+func outermostContentType(inline, attachments []*googlePart) string {
+	switch {
+	case len(attachments) > 0:
+		return "multipart/mixed"
+	case len(inline) > 0:
+		return "multipart/related"
+	default:
+		return "multipart/alternative"
+	}
+}
+
+// writeMixed writes the multipart/mixed level: the related/alternative
+// content followed by the attachments, each as a direct child of mw.
+func writeMixed(mw *multipart.Writer, bodies, inline, attachments []*googlePart) error {
+	if err := writeRelated(mw, bodies, inline); err != nil {
+		return err
+	}
+
+	for _, p := range attachments {
+		if err := writePart(mw, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeRelated writes the multipart/related level: the alternative content
+// plus the inline resources, nested in their own multipart/related part
+// under mw. If there are no inline resources it writes the alternative
+// content directly as a child of mw instead of nesting.
+//
+// Use writeRelatedBodies instead when mw is itself already the declared
+// multipart/related writer (the inline-image-with-no-attachment case) -
+// nesting here would otherwise wrap the content in a second, redundant
+// multipart/related part.
+func writeRelated(mw *multipart.Writer, bodies, inline []*googlePart) error {
+	if len(inline) == 0 {
+		return writeAlternative(mw, bodies)
+	}
+
+	rw, err := newNestedWriter(mw, "multipart/related")
+	if err != nil {
+		return err
+	}
+
+	if err := writeRelatedBodies(rw, bodies, inline); err != nil {
+		return err
+	}
+
+	return rw.Close()
+}
+
+// writeRelatedBodies writes the alternative content and the inline
+// resources directly onto mw, with no further multipart/related wrapping.
+func writeRelatedBodies(mw *multipart.Writer, bodies, inline []*googlePart) error {
+	if err := writeAlternative(mw, bodies); err != nil {
+		return err
+	}
+
+	for _, p := range inline {
+		if err := writePart(mw, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeAlternative writes the multipart/alternative level: the text and
+// html bodies, nested in their own multipart/alternative part under mw. A
+// single body is written directly as a child of mw instead of being
+// wrapped, since there's nothing to offer alternatives between.
+//
+// Use writeBodies instead when mw is itself already the declared
+// multipart/alternative writer (the plain text+html case, with no inline
+// resources or attachments) - nesting here would otherwise wrap the bodies
+// in a second, redundant multipart/alternative part.
+func writeAlternative(mw *multipart.Writer, bodies []*googlePart) error {
+	if len(bodies) <= 1 {
+		return writeBodies(mw, bodies)
+	}
+
+	aw, err := newNestedWriter(mw, "multipart/alternative")
+	if err != nil {
+		return err
+	}
+
+	if err := writeBodies(aw, bodies); err != nil {
+		return err
+	}
+
+	return aw.Close()
+}
+
+// writeBodies writes each body directly as a child part of mw, with no
+// further wrapping.
+func writeBodies(mw *multipart.Writer, bodies []*googlePart) error {
+	for _, p := range bodies {
+		if err := writePart(mw, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newNestedWriter creates a part on mw whose body is itself a multipart
+// message, and returns the multipart.Writer for that nested part.
+func newNestedWriter(mw *multipart.Writer, contentType string) (*multipart.Writer, error) {
+	// multipart.NewWriter picks a random boundary without touching its
+	// writer, so we can mint one up front to put in the part header before
+	// the part itself exists.
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", fmt.Sprintf("%s; boundary=%s", contentType, boundary))
+
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+
+	nested := multipart.NewWriter(pw)
+	if err := nested.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	return nested, nil
+}
+
+// writePart creates a part on mw from p's header and writes its encoded
+// content into it.
+func writePart(mw *multipart.Writer, p *googlePart) error {
+	pw, err := mw.CreatePart(p.header)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.writeGoogleData(pw)
+	return err
+}
+
+// countingWriter wraps an io.Writer and tallies the number of bytes
+// written to it, so WriteTo can report an accurate byte count even though
+// the MIME tree is written by several independent functions.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,116 @@
+package gmail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteToPlainTextAndHTMLIsNotDoubleWrapped(t *testing.T) {
+	m := &GoogleMessage{}
+	if err := m.SetBody([]byte("plain body"), nil, Text); err != nil {
+		t.Fatalf("SetBody text: %v", err)
+	}
+	if err := m.SetBody([]byte("<p>html body</p>"), nil, HTML); err != nil {
+		t.Fatalf("SetBody html: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.String()
+
+	if n := strings.Count(out, "multipart/alternative"); n != 1 {
+		t.Errorf("expected exactly one multipart/alternative declaration, got %d in:\n%s", n, out)
+	}
+}
+
+func TestWriteToNestsRelatedUnderMixed(t *testing.T) {
+	m := &GoogleMessage{}
+	if err := m.SetBody([]byte("plain body"), nil, Text); err != nil {
+		t.Fatalf("SetBody text: %v", err)
+	}
+	if err := m.SetBody([]byte("<p>html body</p><img src=\"cid:logo\">"), nil, HTML); err != nil {
+		t.Fatalf("SetBody html: %v", err)
+	}
+	if err := m.Embed("logo", []byte("fake-png-bytes"), nil); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if err := m.Attach("report.txt", []byte("report contents"), nil); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"multipart/mixed", "multipart/related", "multipart/alternative"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteToDoesNotDoubleWrapRelatedWithoutAttachments(t *testing.T) {
+	m := &GoogleMessage{}
+	if err := m.SetBody([]byte("plain body"), nil, Text); err != nil {
+		t.Fatalf("SetBody text: %v", err)
+	}
+	if err := m.SetBody([]byte("<p>html body</p><img src=\"cid:logo\">"), nil, HTML); err != nil {
+		t.Fatalf("SetBody html: %v", err)
+	}
+	if err := m.Embed("logo", []byte("fake-png-bytes"), nil); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.String()
+
+	if n := strings.Count(out, "multipart/related"); n != 1 {
+		t.Errorf("expected exactly one multipart/related declaration, got %d in:\n%s", n, out)
+	}
+}
+
+func TestWriteToIsDeterministic(t *testing.T) {
+	build := func() *GoogleMessage {
+		m := &GoogleMessage{}
+		_ = m.SetBody([]byte("body"), nil, Auto)
+		_ = m.Attach("b.txt", []byte("b"), nil)
+		_ = m.Attach("a.txt", []byte("a"), nil)
+		_ = m.Attach("c.txt", []byte("c"), nil)
+		return m
+	}
+
+	var first, second bytes.Buffer
+	if _, err := build().WriteTo(&first); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if _, err := build().WriteTo(&second); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	// The two messages use fresh random MIME boundaries, so strip them
+	// before comparing the rest of the structure.
+	stripBoundaries := func(s string) string {
+		lines := strings.Split(s, "\r\n")
+		for i, line := range lines {
+			if idx := strings.Index(line, "boundary="); idx != -1 {
+				lines[i] = line[:idx]
+			}
+			if strings.HasPrefix(line, "--") {
+				lines[i] = ""
+			}
+		}
+		return strings.Join(lines, "\r\n")
+	}
+
+	if stripBoundaries(first.String()) != stripBoundaries(second.String()) {
+		t.Errorf("expected deterministic output, got:\n%s\n---\n%s", first.String(), second.String())
+	}
+}
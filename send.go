@@ -0,0 +1,246 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// sendConfig holds the options collected from a SendOption list.
+type sendConfig struct {
+	httpClient *http.Client
+	userID     string
+	threadID   string
+	labelIDs   []string
+	retry      retryPolicy
+}
+
+// retryPolicy configures the exponential backoff SendContext uses when a
+// request fails with a retryable typed error (ErrRateLimited, or
+// ErrGoogleStatus with a 5xx code).
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func defaultSendConfig() *sendConfig {
+	return &sendConfig{
+		userID: "me",
+		retry:  retryPolicy{maxAttempts: 1},
+	}
+}
+
+// SendOption configures a single SendContext, Send, or SendBatch call.
+type SendOption func(*sendConfig)
+
+// WithHTTPClient supplies a custom *http.Client - e.g. for proxying or DNS
+// hooks - in place of the one the Gmail API builds from the message's
+// AccessToken.
+func WithHTTPClient(client *http.Client) SendOption {
+	return func(c *sendConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithUserID sends the message as a user other than the default "me".
+func WithUserID(userID string) SendOption {
+	return func(c *sendConfig) {
+		c.userID = userID
+	}
+}
+
+// WithThreadID attaches the sent message to an existing Gmail thread.
+func WithThreadID(threadID string) SendOption {
+	return func(c *sendConfig) {
+		c.threadID = threadID
+	}
+}
+
+// WithLabelIDs applies the given label ids to the message on send.
+func WithLabelIDs(labelIDs ...string) SendOption {
+	return func(c *sendConfig) {
+		c.labelIDs = labelIDs
+	}
+}
+
+// WithRetry enables an exponential backoff retry policy for errors that
+// classify as ErrRateLimited or an ErrGoogleStatus with a 5xx code, up to
+// maxAttempts tries total, starting at baseDelay and doubling up to
+// maxDelay between attempts.
+func WithRetry(maxAttempts int, baseDelay, maxDelay time.Duration) SendOption {
+	return func(c *sendConfig) {
+		c.retry = retryPolicy{maxAttempts: maxAttempts, baseDelay: baseDelay, maxDelay: maxDelay}
+	}
+}
+
+// Send sends the message through GMail using a background context and the
+// default options. Returns the Message-Id header for the sent email.
+//
+// Deprecated: use SendContext, which allows timeouts, cancellation, and the
+// options above.
+func (m *GoogleMessage) Send() (string, error) {
+	return m.SendContext(context.Background())
+}
+
+// SendContext sends the message through GMail, honoring ctx for timeouts
+// and cancellation. Returns the Message-Id header for the sent email.
+func (m *GoogleMessage) SendContext(ctx context.Context, opts ...SendOption) (string, error) {
+	cfg := defaultSendConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	srv, err := newGmailService(ctx, cfg, m)
+	if err != nil {
+		return "", err
+	}
+
+	return m.sendVia(ctx, srv, cfg)
+}
+
+// SendBatch sends msgs through a single shared Gmail service, so bulk
+// senders don't pay OAuth/service setup cost per message. It returns the
+// Message-Id of each successfully sent message alongside the error (nil on
+// success) for the message at the same index.
+func SendBatch(ctx context.Context, msgs []*GoogleMessage, opts ...SendOption) ([]string, []error) {
+	ids := make([]string, len(msgs))
+	errs := make([]error, len(msgs))
+
+	if len(msgs) == 0 {
+		return ids, errs
+	}
+
+	cfg := defaultSendConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// All messages in a batch share one account, so the first message's
+	// AccessToken is used to build the single shared service.
+	srv, err := newGmailService(ctx, cfg, msgs[0])
+	if err != nil {
+		for i := range msgs {
+			errs[i] = err
+		}
+		return ids, errs
+	}
+
+	for i, m := range msgs {
+		ids[i], errs[i] = m.sendVia(ctx, srv, cfg)
+	}
+
+	return ids, errs
+}
+
+// newGmailService builds a gmail.Service for cfg, using cfg.httpClient
+// instead of tokenSource when a custom client was supplied.
+func newGmailService(ctx context.Context, cfg *sendConfig, tokenSource oauth2.TokenSource) (*gmail.Service, error) {
+	clientOpts := []option.ClientOption{option.WithUserAgent("XANTDev/gmail-go")}
+	if cfg.httpClient != nil {
+		clientOpts = append(clientOpts, option.WithHTTPClient(cfg.httpClient))
+	} else {
+		clientOpts = append(clientOpts, option.WithTokenSource(tokenSource))
+	}
+
+	return gmail.NewService(ctx, clientOpts...)
+}
+
+// sendVia writes m, sends it through srv using cfg, and fetches the
+// Message-Id header of the sent message.
+func (m *GoogleMessage) sendVia(ctx context.Context, srv *gmail.Service, cfg *sendConfig) (string, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	gmailMessage := &gmail.Message{
+		Raw:      base64.RawURLEncoding.EncodeToString(buf.Bytes()),
+		ThreadId: cfg.threadID,
+		LabelIds: cfg.labelIDs,
+	}
+
+	var resp *gmail.Message
+	err := withRetry(ctx, cfg.retry, func() error {
+		var sendErr error
+		resp, sendErr = srv.Users.Messages.Send(cfg.userID, gmailMessage).Context(ctx).Do()
+		return wrapGoogleError(sendErr)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sentMsg, err := srv.Users.Messages.Get(cfg.userID, resp.Id).Context(ctx).Do()
+	if err != nil {
+		return "", wrapGoogleError(err)
+	}
+
+	var messageID string
+	if sentMsg.Payload != nil {
+		for _, h := range sentMsg.Payload.Headers {
+			if h.Name == "Message-Id" {
+				messageID = h.Value
+				break
+			}
+		}
+	}
+
+	return messageID, nil
+}
+
+// withRetry calls fn, retrying with exponential backoff while it returns an
+// error that looks transient, up to retry.maxAttempts attempts.
+func withRetry(ctx context.Context, retry retryPolicy, fn func() error) error {
+	if retry.maxAttempts < 1 {
+		retry.maxAttempts = 1
+	}
+
+	var err error
+	delay := retry.baseDelay
+
+	for attempt := 1; attempt <= retry.maxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !isRetryable(err) || attempt == retry.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if delay <= 0 {
+			continue
+		}
+		delay *= 2
+		if retry.maxDelay > 0 && delay > retry.maxDelay {
+			delay = retry.maxDelay
+		}
+	}
+
+	return err
+}
+
+// isRetryable reports whether err is a typed error worth retrying: rate
+// limiting, or a 5xx response from the Gmail API.
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+
+	var status *ErrGoogleStatus
+	if errors.As(err, &status) {
+		return status.Code >= http.StatusInternalServerError
+	}
+
+	return false
+}